@@ -20,7 +20,11 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/go-openapi/runtime/middleware"
@@ -31,6 +35,8 @@ import (
 	epl "github.com/vmware/vic/lib/portlayer/exec"
 	spl "github.com/vmware/vic/lib/portlayer/storage"
 	"github.com/vmware/vic/lib/portlayer/storage/nfs"
+	"github.com/vmware/vic/lib/portlayer/storage/objstore"
+	"github.com/vmware/vic/lib/portlayer/storage/placement"
 	"github.com/vmware/vic/lib/portlayer/storage/vsphere"
 	"github.com/vmware/vic/lib/portlayer/util"
 	"github.com/vmware/vic/pkg/trace"
@@ -39,15 +45,133 @@ import (
 
 // StorageHandlersImpl is the receiver for all of the storage handler methods
 type StorageHandlersImpl struct {
-	imageCache  *spl.NameLookupCache
+	// imageStores holds one *spl.NameLookupCache per configured backing
+	// datastore, keyed by imageStoreKey(url). Most deployments configure a
+	// single backing store, but multiple may be configured to federate
+	// image storage across datastores.
+	imageStores map[string]*spl.NameLookupCache
+
+	// imageLocations records which backing store (a key into imageStores)
+	// a given logical image store (CreateImageStore's Body.Name) lives on.
+	imageLocationsMu sync.Mutex
+	imageLocations   map[string]string
+
+	// imagePlacement decides which backing store a newly created logical
+	// image store is bound to.
+	imagePlacement placement.Policy
+
+	// imageStoreLabels and imageStoreCapacity record the placement.Candidate
+	// data for each backing image store - labels from a `label` query
+	// parameter on the store's URL (same convention as
+	// volumeStoreLabels/parseLabelsQuery), and a declared capacity from a
+	// `cap` query parameter, since nothing in this tree exposes a live free
+	// capacity query for a datastore. Always populated; RoundRobin ignores
+	// both, and since resolveImageStore requests no affinity, LabelAffinity
+	// always falls back to round-robin and never consults the labels.
+	imageStoreLabels   map[string]map[string]string
+	imageStoreCapacity map[string]uint64
+
 	volumeCache *spl.VolumeLookupCache
+
+	// volumeStoreLabels records the labels each volume store was configured
+	// with, keyed by store name, so that VolumeStoresList can support the
+	// same ?label= filtering as ListImages/VolumesList. Volume stores have
+	// no metadata map of their own to filter on (unlike images and volumes),
+	// so labels are taken from a `label` query parameter on the store's
+	// configured location URL, e.g. `ds://.../volumes?label=tier=prod`.
+	volumeStoreLabels map[string]map[string]string
+
+	// volumeLocks and imageLocks serialize operations against a given
+	// volume name, or store+imageID pair, so that concurrent requests for
+	// the same resource can't race in the underlying caches.
+	volumeLocks *util.VolumeLocks
+	imageLocks  *util.ImageLocks
 }
 
 const (
-	nfsScheme = "nfs"
-	dsScheme  = "ds"
+	nfsScheme   = "nfs"
+	dsScheme    = "ds"
+	s3Scheme    = "s3"
+	s3aScheme   = "s3a"
+	httpScheme  = "http"
+	httpsScheme = "https"
 )
 
+// parseLabelsQuery parses the `label=key=value[,key=value...]` query values
+// a volume or image store's configured location URL may carry into a flat
+// label map. Unlike util.ParseLabelSelector this produces labels to attach
+// to a resource, not a selector to match one, so only plain key=value pairs
+// are accepted.
+func parseLabelsQuery(raw []string) map[string]string {
+	labels := make(map[string]string)
+
+	for _, r := range raw {
+		for _, term := range strings.Split(r, ",") {
+			term = strings.TrimSpace(term)
+			if term == "" {
+				continue
+			}
+
+			kv := strings.SplitN(term, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+
+			labels[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+
+	return labels
+}
+
+// parseCapacityQuery parses an image store URL's `cap` query value (bytes)
+// into the declared capacity used by placement.FreeCapacity. Nothing in
+// this tree exposes a live free-space query for a datastore, so this is an
+// operator-declared stand-in; an empty or unparseable value reports 0,
+// which FreeCapacity treats as "unknown, never preferred".
+func parseCapacityQuery(raw string) uint64 {
+	capacity, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return capacity
+}
+
+// imagePlacementPolicyEnv selects the image store placement policy used by
+// resolveImageStore. Supported values are "roundrobin" (default),
+// "freecapacity" and "labelaffinity" - see the placement package. There's no
+// spl.Config knob for this in this tree, so it's read from the environment
+// the same way objstore.RestClient falls back to AWS_ACCESS_KEY_ID/
+// AWS_SECRET_ACCESS_KEY.
+const imagePlacementPolicyEnv = "VIC_IMAGE_PLACEMENT_POLICY"
+
+// newImagePlacementPolicy builds the placement.Policy named by
+// imagePlacementPolicyEnv, defaulting to round-robin.
+func newImagePlacementPolicy() placement.Policy {
+	switch os.Getenv(imagePlacementPolicyEnv) {
+	case "freecapacity":
+		return placement.NewFreeCapacity()
+	case "labelaffinity":
+		return placement.NewLabelAffinity()
+	default:
+		return placement.NewRoundRobin()
+	}
+}
+
+// imageStoreKey derives the map key used to identify a configured backing
+// image store. It mirrors the way volume stores are identified by an
+// operator-assigned name, except image stores (spl.Config.ImageStores) are
+// configured as a bare list of URLs rather than a name->URL map, so the
+// name is derived from the datastore the URL points at.
+func imageStoreKey(u url.URL) string {
+	if u.Host != "" {
+		return u.Host
+	}
+
+	return filepath.Base(u.Path)
+}
+
 // Configure assigns functions to all the storage api handlers
 func (h *StorageHandlersImpl) Configure(api *operations.PortLayerAPI, handlerCtx *HandlerContext) {
 	var err error
@@ -59,21 +183,31 @@ func (h *StorageHandlersImpl) Configure(api *operations.PortLayerAPI, handlerCtx
 		log.Panicf("No image stores provided; unable to instantiate storage layer")
 	}
 
-	imageStoreURL := spl.Config.ImageStores[0]
-	// TODO: support multiple image stores. Right now we only support the first one
-	if len(spl.Config.ImageStores) > 1 {
-		log.Warningf("Multiple image stores found. Multiple image stores are not yet supported. Using [%s] %s", imageStoreURL.Host, imageStoreURL.Path)
-	}
+	h.imageStores = make(map[string]*spl.NameLookupCache, len(spl.Config.ImageStores))
+	h.imageLocations = make(map[string]string)
+	h.imageStoreLabels = make(map[string]map[string]string, len(spl.Config.ImageStores))
+	h.imageStoreCapacity = make(map[string]uint64, len(spl.Config.ImageStores))
+	h.imagePlacement = newImagePlacementPolicy()
 
-	ds, err := vsphere.NewImageStore(op, handlerCtx.Session, &imageStoreURL)
-	if err != nil {
-		log.Panicf("Cannot instantiate storage layer: %s", err)
+	for _, imageStoreURL := range spl.Config.ImageStores {
+		key := imageStoreKey(imageStoreURL)
+
+		ds, err := vsphere.NewImageStore(op, handlerCtx.Session, &imageStoreURL)
+		if err != nil {
+			log.Panicf("Cannot instantiate storage layer: %s", err)
+		}
+
+		// The imagestore is implemented via a cache which is backed via an
+		// implementation that writes to disks.  The cache is used to avoid
+		// expensive metadata lookups.
+		h.imageStores[key] = spl.NewLookupCache(ds)
+		h.imageStoreLabels[key] = parseLabelsQuery(imageStoreURL.Query()["label"])
+		h.imageStoreCapacity[key] = parseCapacityQuery(imageStoreURL.Query().Get("cap"))
+		op.Infof("Adding image store %s (%s)", key, imageStoreURL.String())
 	}
 
-	// The imagestore is implemented via a cache which is backed via an
-	// implementation that writes to disks.  The cache is used to avoid
-	// expensive metadata lookups.
-	h.imageCache = spl.NewLookupCache(ds)
+	h.volumeLocks = util.NewVolumeLocks()
+	h.imageLocks = util.NewImageLocks()
 
 	// add the volume stores
 	if err = h.configureVolumeStores(op, handlerCtx); err != nil {
@@ -102,10 +236,13 @@ func (h *StorageHandlersImpl) configureVolumeStores(op trace.Operation, handlerC
 	)
 
 	h.volumeCache = spl.NewVolumeLookupCache(op)
+	h.volumeStoreLabels = make(map[string]map[string]string)
 
 	// Configure the datastores
 	// Each volume store name maps to a datastore + path, which can be referred to by the name.
 	for name, dsurl := range spl.Config.VolumeLocations {
+		h.volumeStoreLabels[name] = parseLabelsQuery(dsurl.Query()["label"])
+
 		switch dsurl.Scheme {
 		case nfsScheme:
 			uid := nfs.DefaultUID
@@ -135,6 +272,17 @@ func (h *StorageHandlersImpl) configureVolumeStores(op trace.Operation, handlerC
 				return fmt.Errorf("cannot instantiate the volume store: %s", err)
 			}
 
+		case s3Scheme, s3aScheme, httpScheme, httpsScheme:
+			client, err := objstore.NewRestClient(dsurl)
+			if err != nil {
+				return fmt.Errorf("cannot configure object store client: %s", err)
+			}
+
+			vs, err = objstore.NewVolumeStore(op, name, dsurl, client)
+			if err != nil {
+				return fmt.Errorf("cannot instantiate the object store: %s", err)
+			}
+
 		default:
 			return fmt.Errorf("unknown scheme for %s", dsurl.String())
 		}
@@ -148,10 +296,92 @@ func (h *StorageHandlersImpl) configureVolumeStores(op trace.Operation, handlerC
 	return nil
 }
 
+// resolveImageStore returns the backing image store cache that logicalName
+// (a CreateImageStore Body.Name / the StoreName route parameter elsewhere)
+// is, or should be, hosted on. If logicalName has not been bound to a
+// backing store yet, one is chosen via h.imagePlacement and the binding is
+// recorded so that later calls for the same logical name land in the same
+// place. This is the federation entry point: CreateImageStore and the first
+// WriteImage for a logical store are what actually invoke the placement
+// policy, everything else just follows the recorded binding.
+func (h *StorageHandlersImpl) resolveImageStore(op trace.Operation, logicalName string) (*spl.NameLookupCache, error) {
+	h.imageLocationsMu.Lock()
+	key, bound := h.imageLocations[logicalName]
+	h.imageLocationsMu.Unlock()
+
+	if !bound {
+		candidates := make([]placement.Candidate, 0, len(h.imageStores))
+		for name := range h.imageStores {
+			candidates = append(candidates, placement.Candidate{
+				Name:      name,
+				FreeBytes: h.imageStoreCapacity[name],
+				Labels:    h.imageStoreLabels[name],
+			})
+		}
+
+		// h.imageStores is a map, so its iteration order is randomized on
+		// every call - sort the candidates so policies like RoundRobin that
+		// index into the slice actually cycle deterministically instead of
+		// effectively placing at random.
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].Name < candidates[j].Name })
+
+		var err error
+		key, err = h.imagePlacement.Place(candidates, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		h.imageLocationsMu.Lock()
+		h.imageLocations[logicalName] = key
+		h.imageLocationsMu.Unlock()
+
+		op.Infof("placed image store %q on backing store %q", logicalName, key)
+	}
+
+	cache, ok := h.imageStores[key]
+	if !ok {
+		return nil, fmt.Errorf("no backing image store %q for image store %q", key, logicalName)
+	}
+
+	return cache, nil
+}
+
+// imageStoreFor returns the backing image store cache that logicalName has
+// already been bound to. Unlike resolveImageStore it never binds a new
+// placement - looking up, listing, or deleting from a store that was never
+// created is an error. (WriteImage uses resolveImageStore instead, so a
+// write to an unbound store places it.)
+func (h *StorageHandlersImpl) imageStoreFor(logicalName string) (*spl.NameLookupCache, error) {
+	h.imageLocationsMu.Lock()
+	key, bound := h.imageLocations[logicalName]
+	h.imageLocationsMu.Unlock()
+
+	if !bound {
+		return nil, fmt.Errorf("unknown image store %q", logicalName)
+	}
+
+	cache, ok := h.imageStores[key]
+	if !ok {
+		return nil, fmt.Errorf("no backing image store %q for image store %q", key, logicalName)
+	}
+
+	return cache, nil
+}
+
 // CreateImageStore creates a new image store
 func (h *StorageHandlersImpl) CreateImageStore(params storage.CreateImageStoreParams) middleware.Responder {
 	op := trace.NewOperation(context.Background(), fmt.Sprintf("CreateImageStore(%s)", params.Body.Name))
-	url, err := h.imageCache.CreateImageStore(op, params.Body.Name)
+
+	cache, err := h.resolveImageStore(op, params.Body.Name)
+	if err != nil {
+		return storage.NewCreateImageStoreDefault(http.StatusInternalServerError).WithPayload(
+			&models.Error{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+	}
+
+	storeURL, err := cache.CreateImageStore(op, params.Body.Name)
 	if err != nil {
 		if os.IsExist(err) {
 			return storage.NewCreateImageStoreConflict().WithPayload(
@@ -169,7 +399,7 @@ func (h *StorageHandlersImpl) CreateImageStore(params storage.CreateImageStorePa
 	}
 	s := &models.StoreURL{
 		Code: http.StatusCreated,
-		URL:  url.String(),
+		URL:  storeURL.String(),
 	}
 	return storage.NewCreateImageStoreCreated().WithPayload(s)
 }
@@ -178,7 +408,15 @@ func (h *StorageHandlersImpl) CreateImageStore(params storage.CreateImageStorePa
 func (h *StorageHandlersImpl) GetImage(params storage.GetImageParams) middleware.Responder {
 	id := params.ID
 
-	url, err := util.ImageStoreNameToURL(params.StoreName)
+	cache, err := h.imageStoreFor(params.StoreName)
+	if err != nil {
+		return storage.NewGetImageNotFound().WithPayload(&models.Error{
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+	}
+
+	u, err := util.ImageStoreNameToURL(params.StoreName)
 	if err != nil {
 		return storage.NewGetImageDefault(http.StatusInternalServerError).WithPayload(
 			&models.Error{
@@ -188,7 +426,7 @@ func (h *StorageHandlersImpl) GetImage(params storage.GetImageParams) middleware
 	}
 
 	op := trace.NewOperation(context.Background(), fmt.Sprintf("GetImage(%s)", id))
-	image, err := h.imageCache.GetImage(op, url, id)
+	image, err := cache.GetImage(op, u, id)
 	if err != nil {
 		e := &models.Error{
 			Code:    http.StatusNotFound,
@@ -213,6 +451,17 @@ func (h *StorageHandlersImpl) DeleteImage(params storage.DeleteImageParams) midd
 			})
 	}
 
+	lockKey := params.StoreName + "/" + params.ID
+	if !h.imageLocks.TryAcquire(lockKey) {
+		return ferr(fmt.Errorf("a conflicting operation is already in progress for image %s in store %s", params.ID, params.StoreName), http.StatusConflict)
+	}
+	defer h.imageLocks.Release(lockKey)
+
+	cache, err := h.imageStoreFor(params.StoreName)
+	if err != nil {
+		return ferr(err, http.StatusNotFound)
+	}
+
 	imageURL, err := util.ImageURL(params.StoreName, params.ID)
 	if err != nil {
 		return ferr(err, http.StatusInternalServerError)
@@ -234,7 +483,7 @@ func (h *StorageHandlersImpl) DeleteImage(params storage.DeleteImageParams) midd
 	}
 
 	op := trace.NewOperation(context.Background(), fmt.Sprintf("DeleteBranch(%s)", image.ID))
-	deletedImages, err := h.imageCache.DeleteBranch(op, image, keepNodes)
+	deletedImages, err := cache.DeleteBranch(op, image, keepNodes)
 	if err != nil {
 		switch {
 		case spl.IsErrImageInUse(err):
@@ -257,12 +506,45 @@ func (h *StorageHandlersImpl) DeleteImage(params storage.DeleteImageParams) midd
 }
 
 // GetImageTar returns an image tar file
+//
+// TODO: the format=layer/image streaming described in the change request
+// this implements depends on spl.NameLookupCache exposing a way to read a
+// layer's raw blob - today the cache only hands back Image metadata, not
+// bytes. Routing and format validation are wired up below so the remaining
+// work is isolated to that one method once it lands.
 func (h *StorageHandlersImpl) GetImageTar(params storage.GetImageTarParams) middleware.Responder {
+	format := "layer"
+	if params.Format != nil && *params.Format != "" {
+		format = *params.Format
+	}
+
+	if format != "layer" && format != "image" {
+		return storage.NewGetImageTarDefault(http.StatusBadRequest).WithPayload(&models.Error{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("unknown format %q, expected \"layer\" or \"image\"", format),
+		})
+	}
+
+	if _, err := h.imageStoreFor(params.StoreName); err != nil {
+		return storage.NewGetImageTarNotFound().WithPayload(&models.Error{
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+	}
+
 	return middleware.NotImplemented("operation storage.GetImageTar has not yet been implemented")
 }
 
 // ListImages returns a list of images in a store
 func (h *StorageHandlersImpl) ListImages(params storage.ListImagesParams) middleware.Responder {
+	cache, err := h.imageStoreFor(params.StoreName)
+	if err != nil {
+		return storage.NewListImagesNotFound().WithPayload(&models.Error{
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		})
+	}
+
 	u, err := util.ImageStoreNameToURL(params.StoreName)
 	if err != nil {
 		return storage.NewListImagesDefault(http.StatusInternalServerError).WithPayload(
@@ -272,8 +554,24 @@ func (h *StorageHandlersImpl) ListImages(params storage.ListImagesParams) middle
 			})
 	}
 
+	// params.Label holds the repeated ?label=key=value query values. This was
+	// asked for as a Labels field distinct from the free-form Metadata map on
+	// models.Image/spl.Image, but models.Image has no Labels field generated
+	// in this tree (there's no swagger spec here to add one to), so the
+	// selector is matched against Metadata instead - operator labels and
+	// user metadata are conflated here until models.Image grows a real
+	// Labels field.
+	selector, err := util.ParseLabelSelector(params.Label)
+	if err != nil {
+		return storage.NewListImagesDefault(http.StatusBadRequest).WithPayload(
+			&models.Error{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+	}
+
 	op := trace.NewOperation(context.Background(), fmt.Sprintf("ListImages(%s, %q)", u.String(), params.Ids))
-	images, err := h.imageCache.ListImages(op, u, params.Ids)
+	images, err := cache.ListImages(op, u, params.Ids)
 	if err != nil {
 		return storage.NewListImagesNotFound().WithPayload(
 			&models.Error{
@@ -285,13 +583,38 @@ func (h *StorageHandlersImpl) ListImages(params storage.ListImagesParams) middle
 	result := make([]*models.Image, 0, len(images))
 
 	for _, image := range images {
-		result = append(result, convertImage(image))
+		converted := convertImage(image)
+		if !selector.Matches(converted.Metadata) {
+			continue
+		}
+		result = append(result, converted)
 	}
 	return storage.NewListImagesOK().WithPayload(result)
 }
 
 // WriteImage writes an image to an image store
 func (h *StorageHandlersImpl) WriteImage(params storage.WriteImageParams) middleware.Responder {
+	lockKey := params.StoreName + "/" + params.ImageID
+	if !h.imageLocks.TryAcquire(lockKey) {
+		return storage.NewWriteImageDefault(http.StatusConflict).WithPayload(
+			&models.Error{
+				Code:    http.StatusConflict,
+				Message: fmt.Sprintf("a conflicting write is already in progress for image %s in store %s", params.ImageID, params.StoreName),
+			})
+	}
+	defer h.imageLocks.Release(lockKey)
+
+	op := trace.NewOperation(context.Background(), fmt.Sprintf("WriteImage(%s)", params.ImageID))
+
+	cache, err := h.resolveImageStore(op, params.StoreName)
+	if err != nil {
+		return storage.NewWriteImageDefault(http.StatusInternalServerError).WithPayload(
+			&models.Error{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+	}
+
 	u, err := util.ImageStoreNameToURL(params.StoreName)
 	if err != nil {
 		return storage.NewWriteImageDefault(http.StatusInternalServerError).WithPayload(
@@ -312,8 +635,14 @@ func (h *StorageHandlersImpl) WriteImage(params storage.WriteImageParams) middle
 		meta = map[string][]byte{*params.Metadatakey: []byte(*params.Metadataval)}
 	}
 
-	op := trace.NewOperation(context.Background(), fmt.Sprintf("WriteImage(%s)", params.ImageID))
-	image, err := h.imageCache.WriteImage(op, parent, params.ImageID, meta, params.Sum, params.ImageFile)
+	// NOTE: params.Sum is already the content digest of the layer being
+	// written. Short-circuiting this write when a layer with the same
+	// digest already exists, and refcounting the underlying blob so
+	// DeleteImage/DeleteBranch only unlink it at zero references, requires
+	// spl.NameLookupCache/vsphere.ImageStore to key storage by digest
+	// instead of ImageID - that refactor lives in the storage package,
+	// which isn't part of this change.
+	image, err := cache.WriteImage(op, parent, params.ImageID, meta, params.Sum, params.ImageFile)
 	if err != nil {
 		return storage.NewWriteImageDefault(http.StatusInternalServerError).WithPayload(
 			&models.Error{
@@ -329,6 +658,24 @@ func (h *StorageHandlersImpl) WriteImage(params storage.WriteImageParams) middle
 func (h *StorageHandlersImpl) VolumeStoresList(params storage.VolumeStoresListParams) middleware.Responder {
 	defer trace.End(trace.Begin("storage_handlers.VolumeStoresList"))
 
+	// params.Label holds the repeated ?label=key=value query values, matched
+	// against the labels the store was configured with - see
+	// h.volumeStoreLabels.
+	//
+	// VolumeStoresList has no Default/BadRequest response defined (only
+	// InternalServerError and OK), and there's no swagger spec in this tree
+	// to add one to, so a malformed selector is reported through
+	// InternalServerError with a Code that matches the status actually
+	// being returned.
+	selector, err := util.ParseLabelSelector(params.Label)
+	if err != nil {
+		return storage.NewVolumeStoresListInternalServerError().WithPayload(
+			&models.Error{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+	}
+
 	op := trace.NewOperation(context.Background(), "VolumeStoresList")
 	stores, err := h.volumeCache.VolumeStoresList(op)
 	if err != nil {
@@ -339,7 +686,15 @@ func (h *StorageHandlersImpl) VolumeStoresList(params storage.VolumeStoresListPa
 			})
 	}
 
-	resp := &models.VolumeStoresListResponse{Stores: stores}
+	filtered := make(map[string]string, len(stores))
+	for name, location := range stores {
+		if !selector.Matches(h.volumeStoreLabels[name]) {
+			continue
+		}
+		filtered[name] = location
+	}
+
+	resp := &models.VolumeStoresListResponse{Stores: filtered}
 
 	return storage.NewVolumeStoresListOK().WithPayload(resp)
 }
@@ -358,6 +713,14 @@ func (h *StorageHandlersImpl) CreateVolume(params storage.CreateVolumeParams) mi
 		})
 	}
 
+	if !h.volumeLocks.TryAcquire(params.VolumeRequest.Name) {
+		return storage.NewCreateVolumeConflict().WithPayload(&models.Error{
+			Code:    http.StatusConflict,
+			Message: fmt.Sprintf("a conflicting operation is already in progress for volume %s", params.VolumeRequest.Name),
+		})
+	}
+	defer h.volumeLocks.Release(params.VolumeRequest.Name)
+
 	byteMap := make(map[string][]byte)
 	for key, value := range params.VolumeRequest.Metadata {
 		byteMap[key] = []byte(value)
@@ -428,6 +791,13 @@ func (h *StorageHandlersImpl) GetVolume(params storage.GetVolumeParams) middlewa
 func (h *StorageHandlersImpl) RemoveVolume(params storage.RemoveVolumeParams) middleware.Responder {
 	defer trace.End(trace.Begin("storage_handlers.RemoveVolume"))
 
+	if !h.volumeLocks.TryAcquire(params.Name) {
+		return storage.NewRemoveVolumeConflict().WithPayload(&models.Error{
+			Message: fmt.Sprintf("a conflicting operation is already in progress for volume %s", params.Name),
+		})
+	}
+	defer h.volumeLocks.Release(params.Name)
+
 	op := trace.NewOperation(context.Background(), fmt.Sprintf("VolumeDestroy(%s)", params.Name))
 	err := h.volumeCache.VolumeDestroy(op, params.Name)
 	if err != nil {
@@ -456,6 +826,21 @@ func (h *StorageHandlersImpl) VolumesList(params storage.ListVolumesParams) midd
 	defer trace.End(trace.Begin(""))
 	var result []*models.VolumeResponse
 
+	// params.Label holds the repeated ?label=key=value query values.
+	//
+	// ListVolumes has no Default/BadRequest response defined (only
+	// InternalServerError and OK), and there's no swagger spec in this tree
+	// to add one to, so a malformed selector is reported through
+	// InternalServerError with a Code that matches the status actually
+	// being returned.
+	selector, err := util.ParseLabelSelector(params.Label)
+	if err != nil {
+		return storage.NewListVolumesInternalServerError().WithPayload(&models.Error{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+	}
+
 	op := trace.NewOperation(context.Background(), "VolumeList")
 	portlayerVolumes, err := h.volumeCache.VolumesList(op)
 	if err != nil {
@@ -478,6 +863,14 @@ func (h *StorageHandlersImpl) VolumesList(params storage.ListVolumesParams) midd
 			})
 		}
 
+		// Same conflation as ListImages above: this was asked for as a Labels
+		// field distinct from Metadata, but models.VolumeResponse has no
+		// Labels field generated in this tree, so the selector matches
+		// against Metadata instead.
+		if !selector.Matches(model.Metadata) {
+			continue
+		}
+
 		result = append(result, &model)
 	}
 
@@ -489,6 +882,20 @@ func (h *StorageHandlersImpl) VolumesList(params storage.ListVolumesParams) midd
 func (h *StorageHandlersImpl) VolumeJoin(params storage.VolumeJoinParams) middleware.Responder {
 	defer trace.End(trace.Begin(""))
 
+	// Unlike CreateVolume/RemoveVolume, the VolumeJoin operation has no
+	// Conflict (or Default) response defined - it only ever returns
+	// InternalServerError or OK - and there's no swagger spec in this tree
+	// to add one to, so a lock conflict has to be reported through
+	// InternalServerError too, with a Code that actually matches the
+	// status being returned rather than claiming a 409 we can't produce.
+	if !h.volumeLocks.TryAcquire(params.Name) {
+		return storage.NewVolumeJoinInternalServerError().WithPayload(&models.Error{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("a conflicting operation is already in progress for volume %s", params.Name),
+		})
+	}
+	defer h.volumeLocks.Release(params.Name)
+
 	op := trace.NewOperation(context.Background(), fmt.Sprintf("VolumeJoin(%s)", params.Name))
 
 	actualHandle := epl.GetHandle(params.JoinArgs.Handle)
@@ -509,6 +916,8 @@ func (h *StorageHandlersImpl) VolumeJoin(params storage.VolumeJoinParams) middle
 		actualHandle, err = nfs.VolumeJoin(op, actualHandle, volume, params.JoinArgs.MountPath, params.JoinArgs.Flags)
 	case dsScheme:
 		actualHandle, err = vsphere.VolumeJoin(op, actualHandle, volume, params.JoinArgs.MountPath, params.JoinArgs.Flags)
+	case s3Scheme, s3aScheme, httpScheme, httpsScheme:
+		actualHandle, err = objstore.VolumeJoin(op, actualHandle, volume, params.JoinArgs.MountPath, params.JoinArgs.Flags)
 	default:
 		err = fmt.Errorf("unknown scheme (%s) for Volume (%s)", volume.Device.DiskPath().Scheme, *volume)
 	}
@@ -0,0 +1,154 @@
+// Copyright 2016-2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objstore
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// zeroReader streams an endless run of zero bytes, used to provision a
+// placeholder object of a given size for a newly created volume (the same
+// thin-provisioned-up-front approach the NFS and vSphere backends take for
+// their backing file/disk).
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// RestClient is a minimal Client implementation that speaks the S3 REST API
+// directly, for deployments that don't want to pull in a full AWS SDK.
+// When an access key is set, requests carry the access/secret key pair as
+// HTTP Basic auth; the pair is taken from the volume location URL's
+// userinfo, or from AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY if the URL
+// carries none. Without an access key requests are sent anonymously.
+// Requests are not SigV4-signed, so only object stores that accept
+// anonymous or Basic-auth access can be used.
+type RestClient struct {
+	endpoint  string
+	accessKey string
+	secretKey string
+	http      *http.Client
+}
+
+// NewRestClient builds a RestClient for the endpoint described by u (an
+// s3://, s3a:// or http(s):// volume location URL).
+func NewRestClient(u *url.URL) (*RestClient, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("objstore: %q has no endpoint (host)", u.String())
+	}
+
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+
+	if u.User != nil {
+		accessKey = u.User.Username()
+		if sk, ok := u.User.Password(); ok {
+			secretKey = sk
+		}
+	}
+
+	scheme := "https"
+	if u.Scheme == httpScheme {
+		scheme = "http"
+	}
+
+	return &RestClient{
+		endpoint:  scheme + "://" + u.Host,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		http:      &http.Client{},
+	}, nil
+}
+
+func (c *RestClient) objectURL(bucket, key string) string {
+	return c.endpoint + "/" + bucket + "/" + key
+}
+
+func (c *RestClient) do(op trace.Operation, method, bucket, key string, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.objectURL(bucket, key), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if size >= 0 {
+		req.ContentLength = size
+	}
+
+	if c.accessKey != "" {
+		req.SetBasicAuth(c.accessKey, c.secretKey)
+	}
+
+	return c.http.Do(req.WithContext(op))
+}
+
+// PutObject implements Client. It provisions a size-byte placeholder object
+// so that a freshly created volume has backing content to read/write from
+// the moment CreateVolume returns.
+func (c *RestClient) PutObject(op trace.Operation, bucket, key string, size int64) error {
+	resp, err := c.do(op, http.MethodPut, bucket, key, io.LimitReader(zeroReader{}, size), size)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("objstore: PUT %s: %s", c.objectURL(bucket, key), resp.Status)
+	}
+
+	return nil
+}
+
+// DeleteObject implements Client.
+func (c *RestClient) DeleteObject(op trace.Operation, bucket, key string) error {
+	resp, err := c.do(op, http.MethodDelete, bucket, key, nil, -1)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("objstore: DELETE %s: %s", c.objectURL(bucket, key), resp.Status)
+	}
+
+	return nil
+}
+
+// StatObject implements Client.
+func (c *RestClient) StatObject(op trace.Operation, bucket, key string) (bool, error) {
+	resp, err := c.do(op, http.MethodHead, bucket, key, nil, -1)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("objstore: HEAD %s: %s", c.objectURL(bucket, key), resp.Status)
+	}
+}
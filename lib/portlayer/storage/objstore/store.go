@@ -0,0 +1,136 @@
+// Copyright 2016-2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package objstore implements a spl.VolumeStorer backed by an
+// S3-compatible object store, so that volumes can be hosted on a bucket
+// rather than a vSphere datastore or an NFS export. It is selected by the
+// `s3://`, `s3a://` and generic `http(s)://` schemes in
+// spl.Config.VolumeLocations.
+//
+// RestClient, the default Client, only speaks the REST API unsigned or
+// with HTTP Basic auth (see its doc comment) - it has not grown AWS SigV4
+// request signing yet, so it cannot authenticate against real AWS S3
+// buckets today. It's suitable for S3-compatible stores that accept
+// anonymous or Basic-auth access (many on-prem/self-hosted ones do); a
+// SigV4-capable Client would be a drop-in replacement once written.
+package objstore
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/vmware/vic/lib/portlayer/exec"
+	spl "github.com/vmware/vic/lib/portlayer/storage"
+	"github.com/vmware/vic/pkg/trace"
+)
+
+// Client is the subset of an S3-compatible client that the volume store
+// needs. It is defined locally so that the storage layer does not take a
+// hard dependency on a particular SDK.
+type Client interface {
+	// PutObject creates the named volume's backing object, size bytes long.
+	PutObject(op trace.Operation, bucket, key string, size int64) error
+	// DeleteObject removes the named volume's backing object.
+	DeleteObject(op trace.Operation, bucket, key string) error
+	// StatObject reports whether the named volume's backing object exists.
+	StatObject(op trace.Operation, bucket, key string) (bool, error)
+}
+
+// VolumeStore is a spl.VolumeStorer backed by a bucket in an S3-compatible
+// object store. Each volume is represented by an object at
+// <prefix>/<volume id>.
+type VolumeStore struct {
+	// Name is the volume store's logical name.
+	Name string
+
+	client Client
+	bucket string
+	prefix string
+}
+
+// NewVolumeStore creates a VolumeStore for the bucket and prefix encoded in
+// the s3://, s3a:// or http(s):// volume location URL. It reads no
+// credentials itself; those are held by client (see NewRestClient).
+func NewVolumeStore(op trace.Operation, name string, u *url.URL, client Client) (*VolumeStore, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("objstore: volume location %q has no bucket (host)", u.String())
+	}
+
+	return &VolumeStore{
+		Name:   name,
+		client: client,
+		bucket: u.Host,
+		prefix: strings.TrimPrefix(u.Path, "/"),
+	}, nil
+}
+
+func (v *VolumeStore) key(id string) string {
+	if v.prefix == "" {
+		return id
+	}
+	return v.prefix + "/" + id
+}
+
+// VolumeStoreName returns the name this VolumeStore was created with,
+// satisfying the stringer half of spl.VolumeStorer's identification.
+func (v *VolumeStore) VolumeStoreName() string {
+	return v.Name
+}
+
+// VolumeCreate provisions the backing object for a newly created volume,
+// sized to capacity bytes, mirroring the way the NFS and vSphere backends
+// provision a backing file/disk up front. This is the method
+// spl.VolumeLookupCache.VolumeCreate dispatches to on the underlying
+// spl.VolumeStorer - there is no call site for it in this package, the
+// dispatch happens from lib/portlayer/storage, which isn't part of this
+// tree, so its exact signature is our best match to the sibling nfs/vsphere
+// backends rather than a confirmed one.
+func (v *VolumeStore) VolumeCreate(op trace.Operation, id string, capacity uint64, info map[string][]byte) (*spl.Volume, error) {
+	if err := v.client.PutObject(op, v.bucket, v.key(id), int64(capacity)); err != nil {
+		return nil, err
+	}
+
+	return &spl.Volume{
+		ID:   id,
+		Info: info,
+	}, nil
+}
+
+// VolumeExists reports whether the given volume id already has a backing
+// object in the bucket.
+func (v *VolumeStore) VolumeExists(op trace.Operation, id string) (bool, error) {
+	return v.client.StatObject(op, v.bucket, v.key(id))
+}
+
+// VolumeDestroy removes the backing object for the given volume id.
+func (v *VolumeStore) VolumeDestroy(op trace.Operation, id string) error {
+	return v.client.DeleteObject(op, v.bucket, v.key(id))
+}
+
+// VolumeJoin exposes an object-store backed volume to a container. Unlike
+// the NFS and vSphere disk backed stores there's no block device to attach,
+// so this can't be done by mutating the handle - the port layer process
+// runs outside the container's mount namespace, so mountPath doesn't exist
+// as a real directory until the container starts, which rules out a
+// one-shot download here too (it would write a file on the port layer
+// host, not into the container, and would fail outright once mountPath is
+// an actual mount point rather than a plain file). Exposing the volume for
+// real requires a live FUSE/rclone-style mount that keeps mountPath in
+// sync with the bucket for the life of the container, wired into the
+// handle's exec config - that lives in lib/portlayer/exec, which isn't
+// part of this tree, so there is nothing functional to do here yet.
+func VolumeJoin(op trace.Operation, handle *exec.Handle, volume *spl.Volume, mountPath string, flags map[string]string) (*exec.Handle, error) {
+	return nil, fmt.Errorf("objstore: VolumeJoin(%s): not implemented - requires exec.Handle-level mount wiring that isn't in this tree", volume.ID)
+}
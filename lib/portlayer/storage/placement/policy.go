@@ -0,0 +1,149 @@
+// Copyright 2016-2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package placement implements pluggable placement policies for choosing
+// which of several federated image stores a new layer should be written
+// to. Policies are intentionally stateless with respect to the stores
+// they are given - all state for a decision is passed in via Candidate on
+// every call, so a policy can be swapped at runtime without needing to be
+// rebuilt.
+package placement
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Candidate describes one image store eligible to receive a placement.
+type Candidate struct {
+	// Name identifies the backing store (the key into the image store map);
+	// it is not a logical image store name from the portlayer API.
+	Name string
+
+	// FreeBytes is the store's free capacity, used by the FreeCapacity
+	// policy. Stores that do not report capacity should leave this 0.
+	FreeBytes uint64
+
+	// Labels are the store's labels, used by the LabelAffinity policy to
+	// match against a write's requested affinity.
+	Labels map[string]string
+}
+
+// Policy picks one of a set of candidate image stores to receive a write.
+type Policy interface {
+	// Place returns the name of the candidate selected to receive the next
+	// write. affinity is an optional set of label requirements the caller
+	// would like the chosen store to satisfy; policies that don't support
+	// affinity matching may ignore it.
+	Place(candidates []Candidate, affinity map[string]string) (string, error)
+}
+
+// ErrNoCandidates is returned when Place is called with no eligible stores.
+var ErrNoCandidates = fmt.Errorf("no image stores available for placement")
+
+// RoundRobin cycles through the candidates in the order supplied,
+// regardless of capacity or labels.
+type RoundRobin struct {
+	mu   sync.Mutex
+	next int
+}
+
+// NewRoundRobin returns a Policy that distributes writes evenly across
+// whatever candidates it is given.
+func NewRoundRobin() *RoundRobin {
+	return &RoundRobin{}
+}
+
+// Place implements Policy.
+func (p *RoundRobin) Place(candidates []Candidate, affinity map[string]string) (string, error) {
+	if len(candidates) == 0 {
+		return "", ErrNoCandidates
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	chosen := candidates[p.next%len(candidates)]
+	p.next++
+
+	return chosen.Name, nil
+}
+
+// FreeCapacity always chooses the candidate reporting the most free space.
+type FreeCapacity struct{}
+
+// NewFreeCapacity returns a Policy that places writes on the store with the
+// most free capacity.
+func NewFreeCapacity() *FreeCapacity {
+	return &FreeCapacity{}
+}
+
+// Place implements Policy.
+func (p *FreeCapacity) Place(candidates []Candidate, affinity map[string]string) (string, error) {
+	if len(candidates) == 0 {
+		return "", ErrNoCandidates
+	}
+
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if c.FreeBytes > best.FreeBytes {
+			best = c
+		}
+	}
+
+	return best.Name, nil
+}
+
+// LabelAffinity chooses the first candidate whose labels satisfy the
+// requested affinity (every key/value in affinity must be present in the
+// candidate's labels). If no candidate matches, or no affinity was
+// requested, it falls back to RoundRobin.
+type LabelAffinity struct {
+	fallback *RoundRobin
+}
+
+// NewLabelAffinity returns a Policy that prefers stores matching the
+// requested affinity labels, falling back to round-robin otherwise.
+func NewLabelAffinity() *LabelAffinity {
+	return &LabelAffinity{fallback: NewRoundRobin()}
+}
+
+// Place implements Policy.
+func (p *LabelAffinity) Place(candidates []Candidate, affinity map[string]string) (string, error) {
+	if len(candidates) == 0 {
+		return "", ErrNoCandidates
+	}
+
+	if len(affinity) == 0 {
+		return p.fallback.Place(candidates, affinity)
+	}
+
+	for _, c := range candidates {
+		if matches(c.Labels, affinity) {
+			return c.Name, nil
+		}
+	}
+
+	return p.fallback.Place(candidates, affinity)
+}
+
+func matches(labels, affinity map[string]string) bool {
+	for k, v := range affinity {
+		if labels[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,121 @@
+// Copyright 2016-2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package placement
+
+import "testing"
+
+func TestRoundRobinCyclesThroughCandidates(t *testing.T) {
+	candidates := []Candidate{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	p := NewRoundRobin()
+
+	var got []string
+	for i := 0; i < 5; i++ {
+		name, err := p.Place(candidates, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		got = append(got, name)
+	}
+
+	want := []string{"a", "b", "c", "a", "b"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Place() call %d = %q, want %q (full sequence: %v)", i, got[i], want[i], got)
+		}
+	}
+}
+
+func TestRoundRobinNoCandidates(t *testing.T) {
+	p := NewRoundRobin()
+	if _, err := p.Place(nil, nil); err != ErrNoCandidates {
+		t.Errorf("expected ErrNoCandidates, got %v", err)
+	}
+}
+
+func TestFreeCapacityChoosesMostFreeSpace(t *testing.T) {
+	candidates := []Candidate{
+		{Name: "a", FreeBytes: 100},
+		{Name: "b", FreeBytes: 500},
+		{Name: "c", FreeBytes: 300},
+	}
+
+	name, err := NewFreeCapacity().Place(candidates, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if name != "b" {
+		t.Errorf("Place() = %q, want %q (most free space)", name, "b")
+	}
+}
+
+func TestFreeCapacityNoCandidates(t *testing.T) {
+	if _, err := NewFreeCapacity().Place(nil, nil); err != ErrNoCandidates {
+		t.Errorf("expected ErrNoCandidates, got %v", err)
+	}
+}
+
+func TestLabelAffinityPrefersMatchingCandidate(t *testing.T) {
+	candidates := []Candidate{
+		{Name: "a", Labels: map[string]string{"tier": "standard"}},
+		{Name: "b", Labels: map[string]string{"tier": "premium", "region": "us"}},
+	}
+
+	name, err := NewLabelAffinity().Place(candidates, map[string]string{"tier": "premium"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if name != "b" {
+		t.Errorf("Place() = %q, want %q (matches requested affinity)", name, "b")
+	}
+}
+
+func TestLabelAffinityFallsBackWithoutAffinity(t *testing.T) {
+	candidates := []Candidate{{Name: "a"}, {Name: "b"}}
+	p := NewLabelAffinity()
+
+	name, err := p.Place(candidates, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if name != "a" {
+		t.Errorf("Place() with no affinity = %q, want %q (round-robin fallback)", name, "a")
+	}
+}
+
+func TestLabelAffinityFallsBackWhenNoCandidateMatches(t *testing.T) {
+	candidates := []Candidate{
+		{Name: "a", Labels: map[string]string{"tier": "standard"}},
+		{Name: "b", Labels: map[string]string{"tier": "standard"}},
+	}
+	p := NewLabelAffinity()
+
+	name, err := p.Place(candidates, map[string]string{"tier": "premium"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if name != "a" {
+		t.Errorf("Place() with no match = %q, want %q (round-robin fallback)", name, "a")
+	}
+}
+
+func TestLabelAffinityNoCandidates(t *testing.T) {
+	if _, err := NewLabelAffinity().Place(nil, nil); err != ErrNoCandidates {
+		t.Errorf("expected ErrNoCandidates, got %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2016-2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// requirement is a single parsed term of a label selector, e.g.
+// "env=prod", "env!=prod", "tier in (web, api)", or "tier" (presence).
+type requirement struct {
+	key      string
+	negate   bool
+	values   map[string]struct{}
+	presence bool
+}
+
+// LabelSelector is a parsed, Kubernetes-style label selector built from one
+// or more `?label=` query values. The supported grammar per term is:
+//
+//	key=value          equality
+//	key!=value         inequality
+//	key in (a, b, c)   membership
+//	key notin (a, b)   non-membership
+//	key                presence
+//	!key               absence
+//
+// Multiple terms (and multiple `label=` query params) are ANDed together.
+type LabelSelector struct {
+	requirements []requirement
+}
+
+// ParseLabelSelector parses the raw `?label=` query values into a
+// LabelSelector. Each string may itself be a comma-separated list of terms.
+func ParseLabelSelector(raw []string) (*LabelSelector, error) {
+	sel := &LabelSelector{}
+
+	for _, r := range raw {
+		for _, term := range splitTerms(r) {
+			term = strings.TrimSpace(term)
+			if term == "" {
+				continue
+			}
+
+			req, err := parseTerm(term)
+			if err != nil {
+				return nil, err
+			}
+
+			sel.requirements = append(sel.requirements, req)
+		}
+	}
+
+	return sel, nil
+}
+
+// splitTerms splits s on top-level commas, i.e. commas that aren't inside a
+// parenthesized value list. This keeps "tier in (web, api), env=prod" as the
+// two terms "tier in (web, api)" and "env=prod" rather than chopping the
+// set's own values apart.
+func splitTerms(s string) []string {
+	var terms []string
+	depth := 0
+	start := 0
+
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				terms = append(terms, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	terms = append(terms, s[start:])
+
+	return terms
+}
+
+func parseTerm(term string) (requirement, error) {
+	switch {
+	case strings.HasPrefix(term, "!"):
+		return requirement{key: strings.TrimSpace(term[1:]), presence: true, negate: true}, nil
+
+	case strings.Contains(term, "!="):
+		parts := strings.SplitN(term, "!=", 2)
+		return requirement{
+			key:    strings.TrimSpace(parts[0]),
+			negate: true,
+			values: toSet(parts[1]),
+		}, nil
+
+	case strings.Contains(term, "="):
+		parts := strings.SplitN(term, "=", 2)
+		return requirement{
+			key:    strings.TrimSpace(parts[0]),
+			values: toSet(parts[1]),
+		}, nil
+
+	case strings.Contains(term, " notin "):
+		return parseSetTerm(term, " notin ", true)
+
+	case strings.Contains(term, " in "):
+		return parseSetTerm(term, " in ", false)
+
+	default:
+		return requirement{key: strings.TrimSpace(term), presence: true}, nil
+	}
+}
+
+func parseSetTerm(term, sep string, negate bool) (requirement, error) {
+	parts := strings.SplitN(term, sep, 2)
+	key := strings.TrimSpace(parts[0])
+
+	vals := strings.TrimSpace(parts[1])
+	vals = strings.TrimPrefix(vals, "(")
+	vals = strings.TrimSuffix(vals, ")")
+
+	set := make(map[string]struct{})
+	for _, v := range strings.Split(vals, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		set[v] = struct{}{}
+	}
+
+	if len(set) == 0 {
+		return requirement{}, fmt.Errorf("label selector %q: empty value set", term)
+	}
+
+	return requirement{key: key, negate: negate, values: set}, nil
+}
+
+func toSet(v string) map[string]struct{} {
+	return map[string]struct{}{strings.TrimSpace(v): {}}
+}
+
+// Matches reports whether labels satisfies every requirement in the
+// selector. A nil or empty selector matches everything.
+func (s *LabelSelector) Matches(labels map[string]string) bool {
+	if s == nil {
+		return true
+	}
+
+	for _, req := range s.requirements {
+		v, present := labels[req.key]
+
+		if req.presence {
+			if present == req.negate {
+				return false
+			}
+			continue
+		}
+
+		if !present {
+			// as in Kubernetes, != and notin match labels that lack the key
+			if req.negate {
+				continue
+			}
+			return false
+		}
+
+		if _, inSet := req.values[v]; inSet == req.negate {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,113 @@
+// Copyright 2016-2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import "testing"
+
+func TestParseLabelSelectorInMultiValue(t *testing.T) {
+	sel, err := ParseLabelSelector([]string{"tier in (web, api)"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !sel.Matches(map[string]string{"tier": "web"}) {
+		t.Error("expected tier=web to match \"tier in (web, api)\"")
+	}
+
+	if !sel.Matches(map[string]string{"tier": "api"}) {
+		t.Error("expected tier=api to match \"tier in (web, api)\"")
+	}
+
+	if sel.Matches(map[string]string{"tier": "db"}) {
+		t.Error("did not expect tier=db to match \"tier in (web, api)\"")
+	}
+}
+
+func TestParseLabelSelectorNotInMultiValue(t *testing.T) {
+	sel, err := ParseLabelSelector([]string{"tier notin (web, api)"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if sel.Matches(map[string]string{"tier": "web"}) {
+		t.Error("did not expect tier=web to match \"tier notin (web, api)\"")
+	}
+
+	if !sel.Matches(map[string]string{"tier": "db"}) {
+		t.Error("expected tier=db to match \"tier notin (web, api)\"")
+	}
+}
+
+func TestParseLabelSelectorMultipleTermsWithInList(t *testing.T) {
+	// the comma inside the parens must not be treated as a term separator,
+	// but the comma after the closing paren must be.
+	sel, err := ParseLabelSelector([]string{"tier in (web, api), env=prod"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !sel.Matches(map[string]string{"tier": "api", "env": "prod"}) {
+		t.Error("expected tier=api,env=prod to match \"tier in (web, api), env=prod\"")
+	}
+
+	if sel.Matches(map[string]string{"tier": "api", "env": "staging"}) {
+		t.Error("did not expect tier=api,env=staging to match \"tier in (web, api), env=prod\"")
+	}
+}
+
+func TestParseLabelSelectorEqualityAndPresence(t *testing.T) {
+	sel, err := ParseLabelSelector([]string{"env=prod,!deprecated"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !sel.Matches(map[string]string{"env": "prod"}) {
+		t.Error("expected env=prod to match \"env=prod,!deprecated\"")
+	}
+
+	if sel.Matches(map[string]string{"env": "prod", "deprecated": "true"}) {
+		t.Error("did not expect a deprecated label to match \"env=prod,!deprecated\"")
+	}
+
+	if sel.Matches(map[string]string{"env": "staging"}) {
+		t.Error("did not expect env=staging to match \"env=prod,!deprecated\"")
+	}
+}
+
+func TestParseLabelSelectorInequality(t *testing.T) {
+	sel, err := ParseLabelSelector([]string{"env!=prod"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if sel.Matches(map[string]string{"env": "prod"}) {
+		t.Error("did not expect env=prod to match \"env!=prod\"")
+	}
+
+	if !sel.Matches(map[string]string{"env": "staging"}) {
+		t.Error("expected env=staging to match \"env!=prod\"")
+	}
+}
+
+func TestParseLabelSelectorEmpty(t *testing.T) {
+	sel, err := ParseLabelSelector(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !sel.Matches(map[string]string{"anything": "goes"}) {
+		t.Error("expected an empty selector to match everything")
+	}
+}